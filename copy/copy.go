@@ -0,0 +1,185 @@
+// Package copy provides a single-file copy routine with the hooks an
+// importer needs: timestamp/owner preservation, symlink handling, fsync,
+// destination renaming, and per-file error recovery.
+package copy
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// SymlinkAction controls how Copy treats a src that is itself a symlink.
+type SymlinkAction int
+
+const (
+	// Shallow recreates the symlink at dst, pointing at the same target.
+	Shallow SymlinkAction = iota
+	// Deep copies the file the symlink points to.
+	Deep
+	// SkipSymlink leaves dst untouched.
+	SkipSymlink
+)
+
+// PermissionControl computes the mode Copy should create dst with, given
+// the source file's info. The default, used when CopyOptions.PermissionControl
+// is nil, preserves the source's permission bits.
+type PermissionControl func(srcInfo os.FileInfo) (os.FileMode, error)
+
+// CopyOptions configures a single Copy call. The zero value skips if dst
+// exists, copies permission bits, recreates a symlink src as a symlink
+// rather than following it, and does not fsync.
+type CopyOptions struct {
+	// PreserveTimes chtimes dst to src's modification time after copying.
+	PreserveTimes bool
+
+	// PreserveOwner chowns dst to src's uid/gid after copying. No-op on
+	// platforms without POSIX ownership.
+	PreserveOwner bool
+
+	// OnSymlink is consulted when src is a symlink. A nil OnSymlink treats
+	// every symlink as Shallow.
+	OnSymlink func(src string) SymlinkAction
+
+	// Sync fsyncs dst after the write completes.
+	Sync bool
+
+	// PermissionControl overrides the destination's permission bits. A nil
+	// PermissionControl preserves src's permission bits.
+	PermissionControl PermissionControl
+
+	// NumOfWorkers is accepted for parity with batch copy helpers built on
+	// top of Copy; Copy itself only ever copies one file and ignores it.
+	NumOfWorkers int
+
+	// RenameDestination, when set, is given the chance to rewrite dst
+	// immediately before it's created. Copy uses whatever path it returns.
+	RenameDestination func(src, dst string) (string, error)
+
+	// Skip is consulted before any write happens. Returning true causes
+	// Copy to return nil without touching dst.
+	Skip func(src, dst string) (bool, error)
+
+	// OnError is given the chance to recover from a failure copying this
+	// file. Returning nil suppresses the error.
+	OnError func(src, dst string, err error) error
+}
+
+// Copy copies src to dst according to opt, returning the first error
+// encountered (after giving opt.OnError, if set, the chance to suppress it).
+func Copy(src, dst string, opt CopyOptions) error {
+	err := doCopy(src, dst, opt)
+	if err != nil && opt.OnError != nil {
+		return opt.OnError(src, dst, err)
+	}
+	return err
+}
+
+func doCopy(src, dst string, opt CopyOptions) error {
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	if opt.RenameDestination != nil {
+		dst, err = opt.RenameDestination(src, dst)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opt.Skip != nil {
+		skip, err := opt.Skip(src, dst)
+		if err != nil {
+			return err
+		}
+		if skip {
+			return nil
+		}
+	} else if _, err := os.Stat(dst); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if srcInfo.Mode()&os.ModeSymlink != 0 {
+		action := Shallow
+		if opt.OnSymlink != nil {
+			action = opt.OnSymlink(src)
+		}
+		switch action {
+		case SkipSymlink:
+			return nil
+		case Shallow:
+			target, err := os.Readlink(src)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, dst)
+		case Deep:
+			// Fall through and copy the target's content below.
+			srcInfo, err = os.Stat(src)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	perm := srcInfo.Mode().Perm()
+	if opt.PermissionControl != nil {
+		perm, err = opt.PermissionControl(srcInfo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := copyContent(src, dst, perm, opt.Sync); err != nil {
+		return err
+	}
+
+	if opt.PreserveTimes {
+		mtime := srcInfo.ModTime()
+		if err := os.Chtimes(dst, mtime, mtime); err != nil {
+			return err
+		}
+	}
+
+	if opt.PreserveOwner {
+		if err := preserveOwner(srcInfo, dst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyContent(src, dst string, perm os.FileMode, sync bool) error {
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	if _, err := io.Copy(destFile, sourceFile); err != nil {
+		return err
+	}
+
+	if sync {
+		return destFile.Sync()
+	}
+	return nil
+}
+
+func preserveOwner(srcInfo os.FileInfo, dst string) error {
+	stat, ok := srcInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return os.Chown(dst, int(stat.Uid), int(stat.Gid))
+}
@@ -0,0 +1,186 @@
+package copy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyShallowSymlinkRecreatesLink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(target, []byte("content"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	if err := Copy(link, dst, CopyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.Readlink(dst)
+	if err != nil {
+		t.Fatalf("expected dst to be a symlink: %v", err)
+	}
+	if got != target {
+		t.Errorf("expected link target %q, got %q", target, got)
+	}
+}
+
+func TestCopyDeepSymlinkCopiesContent(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(target, []byte("content"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	opt := CopyOptions{OnSymlink: func(string) SymlinkAction { return Deep }}
+	if err := Copy(link, dst, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	if fi, err := os.Lstat(dst); err != nil || fi.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("expected dst to be a regular file, got mode %v (err %v)", fi.Mode(), err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "content" {
+		t.Errorf("unexpected content: %q", data)
+	}
+}
+
+func TestCopySkipSymlinkLeavesDstUntouched(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(target, []byte("content"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	opt := CopyOptions{OnSymlink: func(string) SymlinkAction { return SkipSymlink }}
+	if err := Copy(link, dst, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+		t.Errorf("expected dst to not exist, got err %v", err)
+	}
+}
+
+func TestCopyRenameDestination(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(src, []byte("content"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	renamed := filepath.Join(dir, "renamed.txt")
+	opt := CopyOptions{
+		RenameDestination: func(src, dst string) (string, error) {
+			return renamed, nil
+		},
+	}
+	if err := Copy(src, filepath.Join(dir, "ignored.txt"), opt); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(renamed); err != nil {
+		t.Errorf("expected content at renamed destination: %v", err)
+	}
+}
+
+func TestCopySkipReturnsWithoutWriting(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(src, []byte("content"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst.txt")
+	opt := CopyOptions{Skip: func(src, dst string) (bool, error) { return true, nil }}
+	if err := Copy(src, dst, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("expected Skip to leave dst untouched, got err %v", err)
+	}
+}
+
+func TestCopyOnErrorSuppressesFailure(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "does-not-exist.txt")
+	dst := filepath.Join(dir, "dst.txt")
+
+	var recovered error
+	opt := CopyOptions{
+		OnError: func(src, dst string, err error) error {
+			recovered = err
+			return nil
+		},
+	}
+	if err := Copy(missing, dst, opt); err != nil {
+		t.Fatalf("expected OnError to suppress the failure, got %v", err)
+	}
+	if recovered == nil {
+		t.Error("expected OnError to be called with the underlying error")
+	}
+}
+
+func TestCopyOnErrorCanPropagateFailure(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "does-not-exist.txt")
+	dst := filepath.Join(dir, "dst.txt")
+
+	sentinel := errors.New("wrapped")
+	opt := CopyOptions{
+		OnError: func(src, dst string, err error) error {
+			return sentinel
+		},
+	}
+	if err := Copy(missing, dst, opt); !errors.Is(err, sentinel) {
+		t.Errorf("expected sentinel error, got %v", err)
+	}
+}
+
+func TestCopyPermissionControlOverridesMode(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(src, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst.txt")
+	opt := CopyOptions{
+		PermissionControl: func(os.FileInfo) (os.FileMode, error) {
+			return 0600, nil
+		},
+	}
+	if err := Copy(src, dst, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", fi.Mode().Perm())
+	}
+}
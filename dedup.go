@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// hashIndexFile is the hash index sidecar, persisted across runs.
+const hashIndexFile = ".gopc-hashes.json"
+
+// contentDirName is the root, under destDir, of the hash-sharded mirror.
+const contentDirName = "content"
+
+// hashIndex tracks which content hashes have already been copied into
+// destDir, and where the canonical copy lives.
+type hashIndex struct {
+	mu   sync.Mutex
+	path string
+	// hash -> canonical path, relative to destDir.
+	entries map[string]string
+}
+
+// loadHashIndex reads destDir's hash index, or returns an empty one.
+func loadHashIndex(destDir string) (*hashIndex, error) {
+	idx := &hashIndex{
+		path:    filepath.Join(destDir, hashIndexFile),
+		entries: make(map[string]string),
+	}
+
+	data, err := os.ReadFile(idx.path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &idx.entries); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// lookup returns the canonical path previously recorded for hash, if any.
+func (idx *hashIndex) lookup(hash string) (string, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	canonical, ok := idx.entries[hash]
+	return canonical, ok
+}
+
+// record associates hash with canonical and persists the index to disk.
+func (idx *hashIndex) record(hash, canonical string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.entries[hash] = canonical
+
+	data, err := json.MarshalIndent(idx.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(idx.path, data, 0666)
+}
+
+// hashShardDirs pre-creates the 256 first-byte shard directories.
+func hashShardDirs(destDir string) error {
+	for i := 0; i < 256; i++ {
+		shard := filepath.Join(destDir, contentDirName, fmt.Sprintf("%02x", i))
+		if err := os.MkdirAll(shard, 0777); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentAddressedPath returns e.g. destDir/content/3a/3ac5...<ext>.
+func contentAddressedPath(destDir, hash, ext string) string {
+	return filepath.Join(destDir, contentDirName, hash[:2], hash+ext)
+}
+
+// copyWithHash copies src to dst, computing its SHA-256 in the same pass.
+func copyWithHash(src, dst string) (hash string, err error) {
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer sourceFile.Close()
+
+	destFile, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	defer destFile.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(destFile, io.TeeReader(sourceFile, h)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyDeduped copies src into currentSession, but only actually writes the
+// bytes once per content hash: subsequent files with the same content are
+// linked to the canonical copy instead. It records a "complete" manifest
+// entry like resumableCopy, so -verify and resume cover deduped imports too.
+func copyDeduped(idx *hashIndex, m *manifest, destDir, currentSession, src string) (string, error) {
+	ext := filepath.Ext(src)
+	linkName := filepath.Join(currentSession, filepath.Base(src))
+
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(linkName); err == nil {
+		fmt.Println("\t    * Destination file already exists. Skipping...")
+		return linkName, nil
+	}
+
+	// Stage to a unique temp file under content/ and rename into place once
+	// hashed; CreateTemp avoids collisions between concurrent workers
+	// copying same-basename files from different cards.
+	stagedFile, err := os.CreateTemp(filepath.Join(destDir, contentDirName), "staging-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	staged := stagedFile.Name()
+	stagedFile.Close()
+	hash, err := copyWithHash(src, staged)
+	if err != nil {
+		os.Remove(staged)
+		return "", err
+	}
+
+	canonical, exists := idx.lookup(hash)
+	canonicalAbs := filepath.Join(destDir, canonical)
+	if exists {
+		os.Remove(staged)
+	} else {
+		canonicalAbs = contentAddressedPath(destDir, hash, ext)
+		if err := os.Rename(staged, canonicalAbs); err != nil {
+			return "", err
+		}
+		canonical, err = filepath.Rel(destDir, canonicalAbs)
+		if err != nil {
+			return "", err
+		}
+		if err := idx.record(hash, canonical); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.Symlink(canonicalAbs, linkName); err != nil {
+		return "", err
+	}
+
+	if err := m.append(manifestEntry{
+		Src:    src,
+		Dst:    linkName,
+		Size:   srcInfo.Size(),
+		SHA256: hash,
+		Mtime:  srcInfo.ModTime(),
+		Status: statusComplete,
+	}); err != nil {
+		return "", err
+	}
+
+	return linkName, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyDedupedLinksSameContentOnce(t *testing.T) {
+	destDir := t.TempDir()
+	if err := hashShardDirs(destDir); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := loadHashIndex(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := loadManifest(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	srcDir := t.TempDir()
+	srcA := filepath.Join(srcDir, "a.jpg")
+	srcB := filepath.Join(srcDir, "b.jpg")
+	if err := os.WriteFile(srcA, []byte("same bytes"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(srcB, []byte("same bytes"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	session := filepath.Join(destDir, "session")
+	if err := os.MkdirAll(session, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	linkA, err := copyDeduped(idx, m, destDir, session, srcA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkB, err := copyDeduped(idx, m, destDir, session, srcB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targetA, err := os.Readlink(linkA)
+	if err != nil {
+		t.Fatalf("%s is not a symlink: %v", linkA, err)
+	}
+	targetB, err := os.Readlink(linkB)
+	if err != nil {
+		t.Fatalf("%s is not a symlink: %v", linkB, err)
+	}
+	if targetA != targetB {
+		t.Errorf("expected both links to point at the same canonical file, got %q and %q", targetA, targetB)
+	}
+	if !filepath.IsAbs(targetA) {
+		t.Errorf("expected an absolute symlink target, got %q", targetA)
+	}
+
+	data, err := os.ReadFile(linkA)
+	if err != nil {
+		t.Fatalf("symlink target is broken: %v", err)
+	}
+	if string(data) != "same bytes" {
+		t.Errorf("unexpected content via symlink: %q", data)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/tajtiattila/metadata"
+)
+
+// timeReader pulls a capture time out of a file's own metadata.
+type timeReader func(path string) (t time.Time, ok bool)
+
+// timeReaders maps a lower-cased file extension to its timeReader.
+var timeReaders = map[string]timeReader{
+	".jpg":  exifTimeReader,
+	".jpeg": exifTimeReader,
+	".nef":  exifTimeReader,
+	".cr2":  exifTimeReader,
+	".arw":  exifTimeReader,
+	".dng":  exifTimeReader,
+	".mov":  videoTimeReader,
+	".mp4":  videoTimeReader,
+}
+
+// exifTimeReader reads the EXIF DateTimeOriginal/DateTime tags.
+func exifTimeReader(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+
+	x, err := exif.Decode(f)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	t, err := x.DateTime()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// videoTimeReader reads the creation time out of a QuickTime/MP4 container.
+func videoTimeReader(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+
+	m, err := metadata.Parse(f)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if m.DateTimeOriginal.Prec > 0 {
+		return m.DateTimeOriginal.Time, true
+	}
+	if m.DateTimeCreated.Prec > 0 {
+		return m.DateTimeCreated.Time, true
+	}
+	return time.Time{}, false
+}
+
+// cameraModelReaders maps a lower-cased file extension to its model reader.
+var cameraModelReaders = map[string]func(path string) (string, bool){
+	".jpg":  exifCameraModel,
+	".jpeg": exifCameraModel,
+	".nef":  exifCameraModel,
+	".cr2":  exifCameraModel,
+	".arw":  exifCameraModel,
+	".dng":  exifCameraModel,
+}
+
+// exifCameraModel reads the EXIF Model tag (e.g. "NIKON Z 6").
+func exifCameraModel(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	x, err := exif.Decode(f)
+	if err != nil {
+		return "", false
+	}
+
+	tag, err := x.Get(exif.Model)
+	if err != nil {
+		return "", false
+	}
+	model, err := tag.StringVal()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(model), true
+}
+
+// cameraModel returns the camera model embedded in path's metadata, if any.
+func cameraModel(path string) (string, bool) {
+	reader, ok := cameraModelReaders[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return "", false
+	}
+	return reader(path)
+}
+
+// captureTime prefers the embedded metadata time, then falls back to
+// filesystem birth time, then modification time.
+func captureTime(path string, fallback func() (bool, time.Time, time.Time)) time.Time {
+	if reader, ok := timeReaders[strings.ToLower(filepath.Ext(path))]; ok {
+		if t, ok := reader(path); ok {
+			return t
+		}
+	}
+
+	hasBirth, birth, mod := fallback()
+	if hasBirth {
+		return birth
+	}
+	return mod
+}
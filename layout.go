@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultLayout reproduces the tool's original, hard-coded session folder
+// name.
+const defaultLayout = "{{.StartTime.Format \"2006-01-02-15-04-05\"}}"
+
+// sessionLayoutData is the data made available to a -layout template when
+// rendering a session's destination directory.
+type sessionLayoutData struct {
+	Year         int
+	Month        time.Month
+	Day          int
+	SessionIndex int
+	StartTime    time.Time
+	EndTime      time.Time
+	CameraModel  string
+	Ext          string
+}
+
+// parseLayout compiles a -layout template.
+func parseLayout(layout string) (*template.Template, error) {
+	return template.New("layout").Parse(layout)
+}
+
+// renderSessionPath executes tmpl against data and joins the result onto
+// destDir, so the template need only describe the path underneath it. Free-
+// text fields like CameraModel come from file metadata the caller doesn't
+// control, so the rendered path is rejected if it escapes destDir (e.g. via
+// a ".." smuggled in through such a field). An absolute rendered path is not
+// rejected; filepath.Join nests it under destDir.
+func renderSessionPath(tmpl *template.Template, destDir string, data sessionLayoutData) (string, error) {
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	joined := filepath.Join(destDir, buf.String())
+	rel, err := filepath.Rel(destDir, joined)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("rendered layout path %q escapes destDir", buf.String())
+	}
+	return joined, nil
+}
+
+// gapOptions selects how session boundaries are determined: either a fixed
+// Gap duration, or Adaptive mode, which derives the threshold from the
+// photo set's own inter-shot interval distribution.
+type gapOptions struct {
+	Gap       time.Duration
+	Adaptive  bool
+	AdaptiveK float64
+}
+
+// sessionBoundary reports whether curr starts a new session given the photo
+// that immediately preceded it in time order.
+type sessionBoundary func(prev, curr Photo) bool
+
+// fixedGapBoundary starts a new session whenever the time between
+// consecutive photos exceeds gap.
+func fixedGapBoundary(gap time.Duration) sessionBoundary {
+	return func(prev, curr Photo) bool {
+		return prev.Time.Add(gap).Before(curr.Time)
+	}
+}
+
+// adaptiveGapBoundary computes the mean and standard deviation of the gaps
+// between consecutive photos in sortedPhotos and splits wherever a gap
+// exceeds mean+k*stddev, rather than a fixed threshold. With too few photos
+// to compute a meaningful distribution, it falls back to fallbackGap.
+func adaptiveGapBoundary(sortedPhotos []Photo, k float64, fallbackGap time.Duration) sessionBoundary {
+	if len(sortedPhotos) < 3 {
+		return fixedGapBoundary(fallbackGap)
+	}
+
+	gaps := make([]float64, 0, len(sortedPhotos)-1)
+	var sum float64
+	for i := 1; i < len(sortedPhotos); i++ {
+		d := sortedPhotos[i].Time.Sub(sortedPhotos[i-1].Time).Seconds()
+		gaps = append(gaps, d)
+		sum += d
+	}
+	mean := sum / float64(len(gaps))
+
+	var variance float64
+	for _, g := range gaps {
+		variance += (g - mean) * (g - mean)
+	}
+	variance /= float64(len(gaps))
+	stddev := math.Sqrt(variance)
+
+	threshold := mean + k*stddev
+	return func(prev, curr Photo) bool {
+		return curr.Time.Sub(prev.Time).Seconds() > threshold
+	}
+}
+
+// assignSessions walks the time-sorted photos, starting a new session
+// whenever boundary reports one, and renders each session's destination
+// directory from layout. It returns one copyJob per photo.
+func assignSessions(destDir string, photos []Photo, boundary sessionBoundary, layout *template.Template) ([]copyJob, error) {
+	jobs := make([]copyJob, 0, len(photos))
+
+	session := 0
+	var currentSession string
+	var sessionStart int
+	for i, photo := range photos {
+		newSession := currentSession == "" || boundary(photos[i-1], photo)
+		if newSession {
+			session++
+			sessionStart = i
+			end := sessionEnd(photos, sessionStart, boundary)
+
+			dir, err := renderSessionPath(layout, destDir, sessionLayoutData{
+				Year:         photo.Time.Year(),
+				Month:        photo.Time.Month(),
+				Day:          photo.Time.Day(),
+				SessionIndex: session,
+				StartTime:    photo.Time,
+				EndTime:      photos[end].Time,
+				CameraModel:  photo.CameraModel,
+				Ext:          filepath.Ext(photo.Path),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("rendering layout for session %d: %w", session, err)
+			}
+			currentSession = dir
+		}
+
+		jobs = append(jobs, copyJob{
+			Photo:   photo,
+			Dest:    filepath.Join(currentSession, filepath.Base(photo.Path)),
+			Session: session,
+		})
+	}
+	return jobs, nil
+}
+
+// sessionEnd returns the index of the last photo in the session starting at
+// start, so EndTime can be included in the layout data up front.
+func sessionEnd(photos []Photo, start int, boundary sessionBoundary) int {
+	end := start
+	for i := start + 1; i < len(photos); i++ {
+		if boundary(photos[i-1], photos[i]) {
+			break
+		}
+		end = i
+	}
+	return end
+}
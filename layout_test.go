@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveGapBoundarySplitsOnOutlierGap(t *testing.T) {
+	base := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	photos := make([]Photo, 0, 9)
+	t0 := base
+	for i := 0; i < 8; i++ {
+		photos = append(photos, Photo{Time: t0})
+		t0 = t0.Add(10 * time.Second)
+	}
+	photos = append(photos, Photo{Time: t0.Add(time.Hour)})
+
+	boundary := adaptiveGapBoundary(photos, 2.5, 3*time.Hour)
+
+	for i := 1; i < len(photos)-1; i++ {
+		if boundary(photos[i-1], photos[i]) {
+			t.Errorf("photo %d: expected no boundary within the burst", i)
+		}
+	}
+	if !boundary(photos[len(photos)-2], photos[len(photos)-1]) {
+		t.Error("expected a boundary at the 1h outlier gap")
+	}
+}
+
+func TestAdaptiveGapBoundaryFallsBackToConfiguredGapWithTooFewPhotos(t *testing.T) {
+	base := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	photos := []Photo{
+		{Time: base},
+		{Time: base.Add(time.Minute)},
+	}
+
+	boundary := adaptiveGapBoundary(photos, 2.5, 30*time.Minute)
+
+	if boundary(photos[0], photos[1]) {
+		t.Error("expected no boundary within the 30m fallback threshold")
+	}
+	if !boundary(photos[0], Photo{Time: photos[0].Time.Add(time.Hour)}) {
+		t.Error("expected a boundary beyond the 30m fallback threshold")
+	}
+}
+
+func TestRenderSessionPathRejectsEscapingCameraModel(t *testing.T) {
+	tmpl, err := parseLayout("by-camera/{{.CameraModel}}/{{.Year}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = renderSessionPath(tmpl, "/home/user/photos", sessionLayoutData{
+		CameraModel: "../../../../etc/cron.d",
+		Year:        2024,
+	})
+	if err == nil {
+		t.Fatal("expected renderSessionPath to reject a path that escapes destDir")
+	}
+}
+
+func TestRenderSessionPathAllowsOrdinaryCameraModel(t *testing.T) {
+	tmpl, err := parseLayout("by-camera/{{.CameraModel}}/{{.Year}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := renderSessionPath(tmpl, "/home/user/photos", sessionLayoutData{
+		CameraModel: "NIKON Z 6",
+		Year:        2024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "/home/user/photos/by-camera/NIKON Z 6/") {
+		t.Errorf("unexpected rendered path: %q", got)
+	}
+}
@@ -2,38 +2,92 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"slices"
 	"strings"
 	"syscall"
 	"time"
-
-	"github.com/djherbis/times"
 )
 
 type Photo struct {
-	Time time.Time
-	Path string
+	Time        time.Time
+	Path        string
+	CameraModel string
 }
 
 func main() {
 	// Create a new context
 	ctx, cancel := context.WithCancel(context.Background())
 
-	if len(os.Args) != 3 {
+	dedup := flag.Bool("dedup", false, "skip files whose content already exists in destDir, storing content once under destDir/content")
+	workers := flag.Int("workers", 4, "number of concurrent parse/copy workers")
+	dryRun := flag.Bool("dry-run", false, "log what would be copied without copying any files (destDir's manifest file is still created)")
+	gap := flag.Duration("gap", 3*time.Hour, "time between shots that starts a new session")
+	adaptiveGap := flag.Bool("adaptive-gap", false, "derive the session gap from this import's own inter-shot interval distribution instead of -gap")
+	adaptiveK := flag.Float64("adaptive-k", 2.5, "in -adaptive-gap mode, split sessions at gaps exceeding mean+k*stddev")
+	layoutFlag := flag.String("layout", defaultLayout, "text/template for each session's destination directory, relative to destDir")
+	verify := flag.Bool("verify", false, "re-hash every file recorded in destDir's manifest and report bit-rot or interrupted copies, then exit without importing")
+	flag.Parse()
+
+	if flag.NArg() != 2 {
 		panic("must pass source and dest dir, in that order")
 	}
+	if *workers < 1 {
+		panic("-workers must be at least 1")
+	}
 
-	sourceDir := os.Args[1]
-	destDir := os.Args[2]
+	sourceDir := flag.Arg(0)
+	destDir, err := filepath.Abs(flag.Arg(1))
+	if err != nil {
+		panic(err)
+	}
 	if err := os.MkdirAll(destDir, 0777); err != nil {
 		panic(err)
 	}
 
+	layout, err := parseLayout(*layoutFlag)
+	if err != nil {
+		panic(fmt.Errorf("parsing -layout: %w", err))
+	}
+
+	manifest, err := loadManifest(destDir)
+	if err != nil {
+		panic(err)
+	}
+	defer manifest.Close()
+
+	if *verify {
+		mismatched, missing, err := verifyManifest(manifest)
+		if err != nil {
+			panic(err)
+		}
+		for _, dst := range mismatched {
+			fmt.Printf("MISMATCH: %s no longer matches its recorded checksum\n", dst)
+		}
+		for _, dst := range missing {
+			fmt.Printf("MISSING: %s is recorded complete but no longer exists\n", dst)
+		}
+		fmt.Printf("VERIFY DONE: %d mismatched, %d missing\n", len(mismatched), len(missing))
+		return
+	}
+
+	var hashes *hashIndex
+	if *dedup {
+		var err error
+		hashes, err = loadHashIndex(destDir)
+		if err != nil {
+			panic(err)
+		}
+		if !*dryRun {
+			if err := hashShardDirs(destDir); err != nil {
+				panic(err)
+			}
+		}
+	}
+
 	// Check if sourceDir and destDir exist and are directories
 	if _, err := os.Stat(sourceDir); os.IsNotExist(err) {
 		fmt.Println("Source directory does not exist.")
@@ -59,132 +113,21 @@ func main() {
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
+		fmt.Println("SIGTERM RECEIVED, DRAINING IN-FLIGHT COPIES...")
 		cancel()
 	}()
 
-	// Scan the source directory for image files and extract timestamps
-	photos, err := scanDirectoryForImages(sourceDir)
-	if err != nil {
+	gapOpt := gapOptions{Gap: *gap, Adaptive: *adaptiveGap, AdaptiveK: *adaptiveK}
+	if err := runPipeline(ctx, sourceDir, destDir, *workers, *dryRun, hashes, manifest, gapOpt, layout); err != nil {
 		panic(err)
 	}
-	slices.SortFunc(photos, func(a Photo, b Photo) int {
-		return a.Time.Compare(b.Time)
-	})
-
-	gap := 3 * time.Hour
-
-	// Copy the photos over
-	sessionCount := 0
-	copiedCount := 0
-	var currentSession string
-	for i, photo := range photos {
-		// sigterm check
-		select {
-		case <-ctx.Done():
-			fmt.Println("SIGTERM RECEIVED, EXITING...")
-			os.Exit(1)
-		default:
-			// Continue your operation
-		}
-
-		// copy the next photo
-		var last *Photo
-		if i > 0 {
-			last = &photos[i-1]
-		}
-
-		// Check for a new session
-		if currentSession == "" || (last != nil && last.Time.Add(gap).Before(photo.Time)) {
-			sessionCount++
-			currentSession = fmt.Sprintf("%s/%s", destDir, photo.Time.Format("2006-01-02-15-04-05"))
-			fmt.Printf("COPYING SESSION %d [%s]\n", sessionCount, currentSession)
-
-			// Ensure the dest dir
-			if err := os.MkdirAll(currentSession, 0777); err != nil {
-				panic(err)
-			}
-		}
-
-		// // XXX: SKIP ALL BUT THE Xth SESSION
-		// if sessionCount != 6 {
-		// 	continue
-		// }
-
-		// Copy the photo
-		dest := filepath.Join(currentSession, filepath.Base(photo.Path))
-		fmt.Printf("\t%d:%d: [%s] => [%s] (%s)\n", i+1, len(photos), photo.Path, dest, photo.Time.Format("2006-01-02-15-04-05"))
-		copyFile(photo.Path, dest)
-		copiedCount++
-	}
-
-	fmt.Println("DONE!")
-}
-
-func scanDirectoryForImages(dirPath string) ([]Photo, error) {
-	var photos []Photo
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && isImageFile(path) {
-			timeStat, err := times.Stat(path)
-			if err != nil {
-				return err
-			}
-			time := timeStat.ModTime()
-			if timeStat.HasBirthTime() {
-				time = timeStat.BirthTime()
-			}
-
-			photos = append(photos, Photo{
-				Time: time,
-				Path: path,
-			})
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	return photos, nil
 }
 
 func isImageFile(filePath string) bool {
 	// Add more image formats if needed
 	switch strings.ToLower(filepath.Ext(filePath)) {
-	case ".jpg", ".jpeg", ".png", ".nef":
+	case ".jpg", ".jpeg", ".png", ".nef", ".cr2", ".arw", ".dng", ".mov", ".mp4":
 		return true
 	}
 	return false
 }
-
-func copyFile(src, dst string) {
-	_, err := os.Stat(dst)
-	if err == nil {
-		fmt.Println("\t    * Destination file already exists. Skipping...")
-		return
-	}
-	if !os.IsNotExist(err) {
-		fmt.Println("Error retrieving destination file info:", err)
-		return
-	}
-
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		fmt.Println("Error opening source file:", err)
-		return
-	}
-	defer sourceFile.Close()
-
-	destFile, err := os.Create(dst)
-	if err != nil {
-		fmt.Println("Error creating destination file:", err)
-		return
-	}
-	defer destFile.Close()
-
-	_, err = io.Copy(destFile, sourceFile)
-	if err != nil {
-		fmt.Println("Error copying file:", err)
-	}
-}
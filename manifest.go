@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ivanpointer/go-photo-copy/copy"
+)
+
+// manifestFile is the sidecar that records every file this tool has
+// copied, so a crashed or SIGTERM'd import can resume without recopying
+// (or silently skipping) partially-written files.
+const manifestFile = ".gopc-manifest.jsonl"
+
+// manifestStatus is the lifecycle state of one manifest entry.
+type manifestStatus string
+
+const (
+	statusPartial  manifestStatus = "partial"
+	statusComplete manifestStatus = "complete"
+)
+
+// manifestEntry is one line of the manifest, describing a single src -> dst
+// copy.
+type manifestEntry struct {
+	Src    string         `json:"src"`
+	Dst    string         `json:"dst"`
+	Size   int64          `json:"size"`
+	SHA256 string         `json:"sha256"`
+	Mtime  time.Time      `json:"mtime"`
+	Status manifestStatus `json:"status"`
+}
+
+// manifest is an append-only JSONL log of copies, keyed in memory by dst so
+// the latest status for a given destination file is fast to look up.
+type manifest struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	entries map[string]manifestEntry
+}
+
+// loadManifest opens (creating if necessary) the manifest sidecar in
+// destDir and replays it to rebuild the in-memory dst -> entry index.
+func loadManifest(destDir string) (*manifest, error) {
+	path := filepath.Join(destDir, manifestFile)
+
+	m := &manifest{path: path, entries: make(map[string]manifestEntry)}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var e manifestEntry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				continue
+			}
+			m.entries[e.Dst] = e
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	m.file = file
+	return m, nil
+}
+
+// Close closes the underlying manifest file.
+func (m *manifest) Close() error {
+	return m.file.Close()
+}
+
+// lookup returns the most recently recorded entry for dst, if any.
+func (m *manifest) lookup(dst string) (manifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[dst]
+	return e, ok
+}
+
+// append writes e as the next line of the manifest and updates the
+// in-memory index.
+func (m *manifest) append(e manifestEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	m.entries[e.Dst] = e
+	return nil
+}
+
+// needsCopy reports whether dst must be (re)copied.
+func (m *manifest) needsCopy(dst string, srcSize int64) bool {
+	e, ok := m.lookup(dst)
+	return !ok || e.Status != statusComplete || e.Size != srcSize
+}
+
+// resumableCopy writes src to dst+".partial", fsyncs, then renames into
+// place, so a crash or SIGTERM never leaves a file at dst that looks
+// complete but isn't.
+func resumableCopy(m *manifest, src, dst string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !m.needsCopy(dst, srcInfo.Size()) {
+		fmt.Println("\t    * Already copied per manifest. Skipping...")
+		return nil
+	}
+
+	if err := m.append(manifestEntry{Src: src, Dst: dst, Size: srcInfo.Size(), Status: statusPartial}); err != nil {
+		return err
+	}
+
+	partial := dst + ".partial"
+	hash, err := copyAndSync(src, partial)
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+	if err := os.Rename(partial, dst); err != nil {
+		return err
+	}
+
+	return m.append(manifestEntry{
+		Src:    src,
+		Dst:    dst,
+		Size:   srcInfo.Size(),
+		SHA256: hash,
+		Mtime:  srcInfo.ModTime(),
+		Status: statusComplete,
+	})
+}
+
+// copyAndSync copies src to dst via the copy package, fsyncing dst, then
+// hashes the written file so its checksum can be recorded in the manifest.
+// dst may be a ".partial" left behind by a prior crash, so it's removed
+// first rather than trusted to copy.Copy's default skip-if-exists.
+func copyAndSync(src, dst string) (sha256Hex string, err error) {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err := copy.Copy(src, dst, copy.CopyOptions{Sync: true, PreserveTimes: true}); err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(dst)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyManifest re-hashes every "complete" entry's destination file and
+// reports any that are missing or no longer match their recorded SHA-256.
+func verifyManifest(m *manifest) (mismatched, missing []string, err error) {
+	m.mu.Lock()
+	entries := make([]manifestEntry, 0, len(m.entries))
+	for _, e := range m.entries {
+		entries = append(entries, e)
+	}
+	m.mu.Unlock()
+
+	for _, e := range entries {
+		if e.Status != statusComplete {
+			continue
+		}
+
+		f, openErr := os.Open(e.Dst)
+		if os.IsNotExist(openErr) {
+			missing = append(missing, e.Dst)
+			continue
+		}
+		if openErr != nil {
+			return nil, nil, openErr
+		}
+
+		h := sha256.New()
+		_, copyErr := io.Copy(h, f)
+		f.Close()
+		if copyErr != nil {
+			return nil, nil, copyErr
+		}
+
+		if hex.EncodeToString(h.Sum(nil)) != e.SHA256 {
+			mismatched = append(mismatched, e.Dst)
+		}
+	}
+	return mismatched, missing, nil
+}
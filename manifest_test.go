@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResumableCopyRecoversFromStalePartial(t *testing.T) {
+	destDir := t.TempDir()
+	m, err := loadManifest(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	src := filepath.Join(destDir, "src.jpg")
+	if err := os.WriteFile(src, []byte("full content"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(destDir, "dst.jpg")
+	if err := os.WriteFile(dst+".partial", []byte("truncate"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := resumableCopy(m, src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "full content" {
+		t.Errorf("expected the stale .partial to be discarded and src copied in full, got %q", data)
+	}
+
+	e, ok := m.lookup(dst)
+	if !ok || e.Status != statusComplete {
+		t.Errorf("expected a complete manifest entry for %s, got %+v (ok=%v)", dst, e, ok)
+	}
+}
+
+func TestResumableCopySkipsWhenAlreadyComplete(t *testing.T) {
+	destDir := t.TempDir()
+	m, err := loadManifest(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	src := filepath.Join(destDir, "src.jpg")
+	if err := os.WriteFile(src, []byte("content"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(destDir, "dst.jpg")
+
+	if err := resumableCopy(m, src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := resumableCopy(m, src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Error("expected resumableCopy to skip a recopy once the manifest says complete")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/djherbis/times"
+)
+
+// copyJob is a photo that has been assigned to a session folder and is
+// ready to be written by a Copy worker.
+type copyJob struct {
+	Photo   Photo
+	Dest    string
+	Session int
+}
+
+// runPipeline walks sourceDir, extracts photo timestamps, and copies the
+// photos into destDir across three pipeline stages connected by channels:
+//
+//	Source -> Parse (N workers) -> [buffer, sort, assign sessions] -> Copy (N workers)
+func runPipeline(ctx context.Context, sourceDir, destDir string, workers int, dryRun bool, hashes *hashIndex, manifest *manifest, gapOpt gapOptions, layout *template.Template) error {
+	paths := sourceWalk(ctx, sourceDir)
+	photos, err := parsePhotos(ctx, paths, workers)
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(photos, func(a, b Photo) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	var boundary sessionBoundary
+	if gapOpt.Adaptive {
+		boundary = adaptiveGapBoundary(photos, gapOpt.AdaptiveK, gapOpt.Gap)
+	} else {
+		boundary = fixedGapBoundary(gapOpt.Gap)
+	}
+
+	jobs, err := assignSessions(destDir, photos, boundary, layout)
+	if err != nil {
+		return err
+	}
+	copiedCount := runCopyWorkers(ctx, jobs, workers, dryRun, hashes, manifest, destDir)
+
+	fmt.Printf("DONE! Copied %d of %d photos across %d sessions.\n", copiedCount, len(photos), sessionCount(jobs))
+	return nil
+}
+
+// sourceWalk walks dirPath in its own goroutine, emitting image file paths
+// on the returned channel, closed once the walk completes or ctx is cancelled.
+func sourceWalk(ctx context.Context, dirPath string) <-chan string {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !isImageFile(path) {
+				return nil
+			}
+			select {
+			case out <- path:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+	}()
+	return out
+}
+
+// parsePhotos fans out a pool of workers to extract a Photo{Time, Path, CameraModel} for
+// each path received from paths, and collects the results.
+func parsePhotos(ctx context.Context, paths <-chan string, workers int) ([]Photo, error) {
+	results := make(chan Photo)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				timeStat, err := times.Stat(path)
+				if err != nil {
+					fmt.Println("Error statting file:", err)
+					continue
+				}
+				capturedAt := captureTime(path, func() (bool, time.Time, time.Time) {
+					return timeStat.HasBirthTime(), timeStat.BirthTime(), timeStat.ModTime()
+				})
+				model, _ := cameraModel(path)
+
+				select {
+				case results <- Photo{Time: capturedAt, Path: path, CameraModel: model}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var photos []Photo
+	for photo := range results {
+		photos = append(photos, photo)
+	}
+	if err := ctx.Err(); err != nil {
+		return photos, err
+	}
+	return photos, nil
+}
+
+// sessionCount returns the number of distinct sessions represented in jobs.
+func sessionCount(jobs []copyJob) int {
+	max := 0
+	for _, j := range jobs {
+		if j.Session > max {
+			max = j.Session
+		}
+	}
+	return max
+}
+
+// runCopyWorkers fans out a pool of workers that create session directories
+// and copy each job's photo into place. It returns the number of jobs that
+// finished without error; files skipped because they were already present
+// at the destination are counted too.
+func runCopyWorkers(ctx context.Context, jobs []copyJob, workers int, dryRun bool, hashes *hashIndex, manifest *manifest, destDir string) int {
+	jobChan := make(chan copyJob)
+	go func() {
+		defer close(jobChan)
+		for _, job := range jobs {
+			select {
+			case jobChan <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var copied int64
+	var mkdirMu sync.Mutex
+	madeDirs := make(map[string]bool)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				sessionDir := filepath.Dir(job.Dest)
+
+				fmt.Printf("\t[session %d] [%s] => [%s] (%s)\n", job.Session, job.Photo.Path, job.Dest, job.Photo.Time.Format("2006-01-02-15-04-05"))
+				if dryRun {
+					continue
+				}
+
+				mkdirMu.Lock()
+				if !madeDirs[sessionDir] {
+					if err := os.MkdirAll(sessionDir, 0777); err != nil {
+						mkdirMu.Unlock()
+						fmt.Println("Error creating session directory:", err)
+						continue
+					}
+					madeDirs[sessionDir] = true
+				}
+				mkdirMu.Unlock()
+
+				if hashes != nil {
+					if _, err := copyDeduped(hashes, manifest, destDir, sessionDir, job.Photo.Path); err != nil {
+						fmt.Println("Error copying file:", err)
+						continue
+					}
+				} else if err := resumableCopy(manifest, job.Photo.Path, job.Dest); err != nil {
+					fmt.Println("Error copying file:", err)
+					continue
+				}
+				atomic.AddInt64(&copied, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return int(copied)
+}
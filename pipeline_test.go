@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestCopyDedupedConcurrentSameBasenameNoRace(t *testing.T) {
+	destDir := t.TempDir()
+	if err := hashShardDirs(destDir); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := loadHashIndex(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := loadManifest(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			cardDir := filepath.Join(destDir, fmt.Sprintf("card-%d", i))
+			if err := os.MkdirAll(cardDir, 0777); err != nil {
+				errs[i] = err
+				return
+			}
+			src := filepath.Join(cardDir, "IMG_0001.jpg")
+			if err := os.WriteFile(src, []byte(fmt.Sprintf("content-%d", i)), 0666); err != nil {
+				errs[i] = err
+				return
+			}
+
+			session := filepath.Join(destDir, fmt.Sprintf("session-%d", i))
+			if err := os.MkdirAll(session, 0777); err != nil {
+				errs[i] = err
+				return
+			}
+
+			_, errs[i] = copyDeduped(idx, m, destDir, session, src)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("worker %d: %v", i, err)
+		}
+	}
+}